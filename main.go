@@ -2,48 +2,32 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"sync"
+	"strings"
 	"time"
+
+	"github.com/yourusername/deployer/pkg/command"
+	"github.com/yourusername/deployer/pkg/daemon"
+	"github.com/yourusername/deployer/pkg/daemon/db"
+	"github.com/yourusername/deployer/pkg/deploy"
+	"github.com/yourusername/deployer/pkg/prisma/binaries"
+	"github.com/yourusername/deployer/pkg/types"
 )
 
 // DeployRequest represents the JSON payload for deployments.
 type DeployRequest struct {
-	RepoURL string `json:"repo_url"`
-}
-
-var (
-	deployMutex sync.Mutex
-)
-
-// Helper function for running shell commands with a timeout
-func runCommand(cmd *exec.Cmd, timeout time.Duration) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	done := make(chan error)
-	var output []byte
-
-	go func() {
-		var err error
-		output, err = cmd.CombinedOutput()
-		done <- err
-	}()
-
-	select {
-	case err := <-done:
-		return output, err
-	case <-ctx.Done():
-		cmd.Process.Kill()
-		return nil, fmt.Errorf("command timed out after %v", timeout)
-	}
+	RepoURL    string `json:"repo_url"`
+	Ref        string `json:"ref,omitempty"`
+	Depth      int    `json:"depth,omitempty"`
+	Submodules bool   `json:"submodules,omitempty"`
+	AuthRef    string `json:"auth_ref,omitempty"`
 }
 
 // copyDir recursively copies a directory
@@ -89,135 +73,190 @@ func copyDir(src, dst string) error {
 	})
 }
 
-func deployHandler(w http.ResponseWriter, r *http.Request) {
-	deployMutex.Lock()
-	defer deployMutex.Unlock()
-
-	// Validate API Key
-	apiKey := r.Header.Get("X-API-Key")
-	if apiKey != os.Getenv("DEPLOY_API_KEY") {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		log.Println("Unauthorized deployment attempt")
-		return
+// publish atomically swaps distPath into the served "static" directory.
+func publish(distPath string) error {
+	newStatic := "static_new"
+	os.RemoveAll(newStatic)
+	if err := copyDir(distPath, newStatic); err != nil {
+		return err
 	}
 
-	// Parse JSON payload
-	var req DeployRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		log.Println("Invalid payload:", err)
-		return
+	oldStatic := "static_old"
+	os.RemoveAll(oldStatic)
+	if err := os.Rename("static", oldStatic); err != nil && !os.IsNotExist(err) {
+		return err
 	}
 
-	// Validate RepoURL
-	if !(len(req.RepoURL) > 3 && req.RepoURL[:4] == "http") {
-		http.Error(w, "Invalid repository URL", http.StatusBadRequest)
-		log.Println("Invalid RepoURL:", req.RepoURL)
-		return
+	if err := os.Rename(newStatic, "static"); err != nil {
+		if rbErr := os.Rename(oldStatic, "static"); rbErr != nil {
+			log.Printf("Rollback failed: %v", rbErr)
+		}
+		return err
 	}
 
-	// Create build directory
-	timestamp := time.Now().UnixNano()
-	buildFolder := fmt.Sprintf("build-%d", timestamp)
-	if err := os.Mkdir(buildFolder, 0755); err != nil {
-		http.Error(w, "Failed to create build directory", http.StatusInternalServerError)
-		log.Println("Mkdir error:", err)
-		return
-	}
-	defer os.RemoveAll(buildFolder)
+	os.RemoveAll(oldStatic)
+	return nil
+}
 
-	// Clone repository
-	cloneCmd := exec.Command("git", "clone", req.RepoURL, buildFolder)
-	cloneOutput, err := runCommand(cloneCmd, 5*time.Minute)
-	if err != nil {
-		log.Printf("Clone failed: %s\nOutput: %s", err, cloneOutput)
-		http.Error(w, "Failed to clone repository", http.StatusInternalServerError)
-		return
+func requireAPIKey(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-API-Key") != os.Getenv("DEPLOY_API_KEY") {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		log.Println("Unauthorized request to", r.URL.Path)
+		return false
 	}
+	return true
+}
 
-	// Check package.json exists
-	if _, err := os.Stat(filepath.Join(buildFolder, "package.json")); os.IsNotExist(err) {
-		http.Error(w, "No package.json found", http.StatusBadRequest)
-		log.Println("No package.json in repository")
-		return
-	}
+// deployHandler enqueues a deployment job and returns its ID immediately;
+// the job is actually run by the daemon's worker pool.
+func deployHandler(d *daemon.Daemon) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAPIKey(w, r) {
+			return
+		}
 
-	// npm install
-	npmInstallCmd := exec.Command("npm", "install")
-	npmInstallCmd.Dir = buildFolder
-	installOutput, err := runCommand(npmInstallCmd, 10*time.Minute)
-	if err != nil {
-		log.Printf("npm install failed: %s\nOutput: %s", err, installOutput)
-		http.Error(w, "npm install failed", http.StatusInternalServerError)
-		return
-	}
+		var req DeployRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			log.Println("Invalid payload:", err)
+			return
+		}
 
-	// npm run build
-	npmBuildCmd := exec.Command("npm", "run", "build")
-	npmBuildCmd.Dir = buildFolder
-	buildOutput, err := runCommand(npmBuildCmd, 10*time.Minute)
-	if err != nil {
-		log.Printf("npm build failed: %s\nOutput: %s", err, buildOutput)
-		http.Error(w, "npm build failed", http.StatusInternalServerError)
-		return
-	}
+		if !(len(req.RepoURL) > 3 && req.RepoURL[:4] == "http") {
+			http.Error(w, "Invalid repository URL", http.StatusBadRequest)
+			log.Println("Invalid RepoURL:", req.RepoURL)
+			return
+		}
 
-	// Verify build output
-	distPath := filepath.Join(buildFolder, "dist")
-	if _, err := os.Stat(distPath); os.IsNotExist(err) {
-		http.Error(w, "Build output 'dist' not found", http.StatusInternalServerError)
-		log.Println("Build output missing")
-		return
-	}
+		jobID, err := d.Enqueue(req.RepoURL, daemon.EnqueueOptions{
+			Ref:        req.Ref,
+			Depth:      req.Depth,
+			Submodules: req.Submodules,
+			AuthRef:    req.AuthRef,
+		})
+		if err != nil {
+			http.Error(w, "Failed to enqueue deployment", http.StatusInternalServerError)
+			log.Println("Enqueue error:", err)
+			return
+		}
 
-	// Prepare new static directory
-	newStatic := "static_new"
-	os.RemoveAll(newStatic)
-	if err := copyDir(distPath, newStatic); err != nil {
-		log.Printf("Copy failed: %v", err)
-		http.Error(w, "Failed to prepare deployment", http.StatusInternalServerError)
-		return
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"job_id": jobID})
+		log.Println("Deployment queued for repo:", req.RepoURL, "job:", jobID)
 	}
+}
 
-	// Atomic swap
-	oldStatic := "static_old"
-	os.RemoveAll(oldStatic)
-	if err := os.Rename("static", oldStatic); err != nil && !os.IsNotExist(err) {
-		log.Printf("Rename static failed: %v", err)
-		http.Error(w, "Deployment failed", http.StatusInternalServerError)
-		return
+// jobsHandler lists every job the daemon has recorded.
+func jobsHandler(d *daemon.Daemon) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAPIKey(w, r) {
+			return
+		}
+		jobs, err := d.Jobs()
+		if err != nil {
+			http.Error(w, "Failed to list jobs", http.StatusInternalServerError)
+			log.Println("List jobs error:", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jobs)
 	}
+}
 
-	if err := os.Rename(newStatic, "static"); err != nil {
-		log.Printf("Atomic swap failed: %v", err)
-		// Attempt rollback
-		if err := os.Rename(oldStatic, "static"); err != nil {
-			log.Printf("Rollback failed: %v", err)
+// jobHandler serves GET /jobs/{id} and POST /jobs/{id}/cancel.
+func jobHandler(d *daemon.Daemon) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAPIKey(w, r) {
+			return
+		}
+		rest := strings.TrimPrefix(r.URL.Path, "/jobs/")
+		if id, ok := strings.CutSuffix(rest, "/cancel"); ok && r.Method == http.MethodPost {
+			if ok := d.Cancel(id); !ok {
+				http.Error(w, "Job not found or not running", http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return
 		}
-		http.Error(w, "Deployment failed", http.StatusInternalServerError)
-		return
-	}
 
-	// Cleanup old static
-	os.RemoveAll(oldStatic)
+		job, err := d.Job(rest)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch job", http.StatusInternalServerError)
+			log.Println("Get job error:", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	}
+}
 
-	// Respond
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Deployment successful",
-	})
-	log.Println("Deployment succeeded for repo:", req.RepoURL)
+// repoJobsHandler serves GET /repos/{url}/jobs.
+func repoJobsHandler(d *daemon.Daemon) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAPIKey(w, r) {
+			return
+		}
+		repoURL := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/repos/"), "/jobs")
+		jobs, err := d.RepoJobs(repoURL)
+		if err != nil {
+			http.Error(w, "Failed to list jobs", http.StatusInternalServerError)
+			log.Println("List repo jobs error:", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(jobs)
+	}
 }
 
 func main() {
-	// Ensure static directory existsekili
+	// Ensure static directory exists
 	if _, err := os.Stat("static"); os.IsNotExist(err) {
 		os.Mkdir("static", 0755)
 	}
 
-	http.HandleFunc("/deploy", deployHandler)
+	store, err := db.Open("deployer.db")
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer store.Close()
+
+	log.Println("Pre-fetching Prisma engine binaries...")
+	prefetchCtx, cancelPrefetch := context.WithTimeout(context.Background(), 5*time.Minute)
+	if err := binaries.PrefetchAll(prefetchCtx); err != nil {
+		log.Printf("Prisma engine prefetch failed (will fall back to per-build downloads): %v", err)
+	}
+	cancelPrefetch()
+
+	deployer := deploy.NewDeployer(command.RunWithEnv)
+	deployer.BuildCacheDir = "build-cache"
+	deployer.BuildCacheMaxBytes = 5 << 30 // 5 GiB
+	deployer.CommitCache = func(repoURL, commitSHA string) (string, bool) {
+		repo, err := store.GetRepo(repoURL)
+		if err != nil || repo.LastCommitSHA != commitSHA || repo.LastJobID == "" {
+			return "", false
+		}
+		job, err := store.GetJob(repo.LastJobID)
+		if err != nil || job.DeployPath == "" {
+			return "", false
+		}
+		return job.DeployPath, true
+	}
+	d := daemon.New(store, deployer, "builds")
+	d.Publish = func(deployment *types.Deployment) error {
+		return publish(filepath.Join(deployment.BuildDir, deployment.OutputDir))
+	}
+
+	http.HandleFunc("/deploy", deployHandler(d))
+	http.HandleFunc("/jobs", jobsHandler(d))
+	http.HandleFunc("/jobs/", jobHandler(d))
+	http.HandleFunc("/repos/", repoJobsHandler(d))
 	http.Handle("/", http.FileServer(http.Dir("./static")))
 
 	log.Println("Server starting on :8080")
 	log.Fatal(http.ListenAndServe(":8080", nil))
-}
\ No newline at end of file
+}
@@ -0,0 +1,311 @@
+// Package buildcache caches a project's installed dependencies (node_modules
+// and framework-specific cache directories) across deploys, keyed by a hash
+// of its lockfile, so unchanged dependencies don't have to be reinstalled
+// from scratch on every build even when the commit changes.
+package buildcache
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// lockfiles are checked in priority order; the first one present is hashed.
+var lockfiles = []string{"package-lock.json", "pnpm-lock.yaml", "yarn.lock"}
+
+// extraCacheDirs maps a framework name (see pkg/framework) to the extra
+// directories, beyond node_modules, worth caching between builds.
+var extraCacheDirs = map[string]string{
+	"next":      ".next/cache",
+	"nuxt":      ".nuxt",
+	"sveltekit": ".svelte-kit",
+	"vite":      "node_modules/.vite",
+}
+
+// DirsFor returns the directories, relative to a build dir, that should be
+// cached for a project built with the named framework.
+func DirsFor(frameworkName string) []string {
+	dirs := []string{"node_modules"}
+	if extra, ok := extraCacheDirs[frameworkName]; ok {
+		dirs = append(dirs, extra)
+	}
+	return dirs
+}
+
+// Key hashes whichever lockfile is present in buildDir, so any commit with an
+// unchanged dependency tree maps to the same cache entry rather than forcing
+// a fresh install on every new commit.
+func Key(buildDir string) (string, error) {
+	h := sha256.New()
+	found := false
+	for _, name := range lockfiles {
+		data, err := os.ReadFile(filepath.Join(buildDir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", name, err)
+		}
+		found = true
+		fmt.Fprintf(h, "%s\n", name)
+		h.Write(data)
+		break
+	}
+	if !found {
+		return "", fmt.Errorf("no lockfile found in %s", buildDir)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func archivePath(cacheDir, key string) string {
+	return filepath.Join(cacheDir, key+".tar.zst")
+}
+
+// Restore extracts the cached archive for key into buildDir, if present. It
+// reports whether there was a cache hit.
+func Restore(cacheDir, key, buildDir string) (bool, error) {
+	path := archivePath(cacheDir, key)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("open cache entry: %w", err)
+	}
+	defer f.Close()
+
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return false, fmt.Errorf("open zstd stream: %w", err)
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("read tar entry: %w", err)
+		}
+
+		dest := filepath.Join(buildDir, hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, os.FileMode(hdr.Mode)); err != nil {
+				return false, err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return false, err
+			}
+			os.Remove(dest)
+			if err := os.Symlink(hdr.Linkname, dest); err != nil {
+				return false, err
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return false, err
+			}
+			out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return false, err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return false, err
+			}
+			out.Close()
+		}
+	}
+
+	os.Chtimes(path, time.Now(), time.Now()) // bump mtime for LRU eviction
+	return true, nil
+}
+
+// Store archives dirs (relative to buildDir) into the cache under key,
+// skipping node_modules/.bin symlinks whose absolute targets lie inside
+// buildDir (see isStaleBinSymlink). Other symlinks, including relative .bin
+// links, are archived as they are.
+func Store(cacheDir, key, buildDir string, dirs []string) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+
+	tmp := archivePath(cacheDir, key) + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("create cache entry: %w", err)
+	}
+
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("open zstd writer: %w", err)
+	}
+	tw := tar.NewWriter(zw)
+
+	for _, dir := range dirs {
+		src := filepath.Join(buildDir, dir)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := addDir(tw, buildDir, dir); err != nil {
+			tw.Close()
+			zw.Close()
+			f.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		zw.Close()
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("finalize tar: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("finalize zstd stream: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, archivePath(cacheDir, key))
+}
+
+func addDir(tw *tar.Writer, buildDir, relDir string) error {
+	root := filepath.Join(buildDir, relDir)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(buildDir, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if isStaleBinSymlink(relPath, path, buildDir) {
+				return nil
+			}
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			hdr, err := tar.FileInfoHeader(info, target)
+			if err != nil {
+				return err
+			}
+			hdr.Name = relPath
+			return tw.WriteHeader(hdr)
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = relPath
+		if d.IsDir() {
+			hdr.Name += "/"
+			return tw.WriteHeader(hdr)
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+}
+
+// isStaleBinSymlink reports whether path is a node_modules/.bin symlink that
+// points at an absolute location inside this particular build dir: those
+// targets won't exist once the archive is restored into a fresh build dir,
+// and the package manager recreates node_modules/.bin on install anyway.
+func isStaleBinSymlink(relPath, path, buildDir string) bool {
+	if !strings.Contains(relPath, string(filepath.Separator)+".bin"+string(filepath.Separator)) {
+		return false
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return true
+	}
+	if !filepath.IsAbs(target) {
+		return false
+	}
+	absBuildDir, err := filepath.Abs(buildDir)
+	if err != nil {
+		return true
+	}
+	return strings.HasPrefix(target, absBuildDir)
+}
+
+// Evict removes the least-recently-used cache entries until the cache
+// directory's total size is at or under maxBytes.
+func Evict(cacheDir string, maxBytes int64) error {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read cache dir: %w", err)
+	}
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []entry
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".tar.zst") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, entry{filepath.Join(cacheDir, e.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	for _, f := range files {
+		if total <= maxBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil {
+			continue
+		}
+		total -= f.size
+	}
+	return nil
+}
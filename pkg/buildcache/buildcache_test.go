@@ -0,0 +1,112 @@
+package buildcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeySameAcrossCommitsWithUnchangedLockfile(t *testing.T) {
+	dirA := t.TempDir()
+	dirB := t.TempDir()
+	lockfile := []byte(`{"a":1}`)
+	if err := os.WriteFile(filepath.Join(dirA, "package-lock.json"), lockfile, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dirB, "package-lock.json"), lockfile, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keyA, err := Key(dirA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, err := Key(dirB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyA != keyB {
+		t.Error("Key() should be identical for two commits sharing an unchanged lockfile")
+	}
+}
+
+func TestKeyChangesWithLockfileContents(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(`{"a":1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key1, err := Key(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(`{"a":2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key2, err := Key(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key1 == key2 {
+		t.Error("Key() should differ when the lockfile contents change")
+	}
+}
+
+func TestKeyRequiresALockfile(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Key(dir); err == nil {
+		t.Error("Key() should fail when no known lockfile is present")
+	}
+}
+
+func TestIsStaleBinSymlink(t *testing.T) {
+	buildDir := t.TempDir()
+	binDir := filepath.Join(buildDir, "node_modules", ".bin")
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	absBuildDir, err := filepath.Abs(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staleLink := filepath.Join(binDir, "stale")
+	if err := os.Symlink(filepath.Join(absBuildDir, "node_modules", "foo", "bin"), staleLink); err != nil {
+		t.Fatal(err)
+	}
+	relPath, err := filepath.Rel(buildDir, staleLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isStaleBinSymlink(relPath, staleLink, buildDir) {
+		t.Error("isStaleBinSymlink() = false for a symlink into the build dir, want true")
+	}
+
+	externalLink := filepath.Join(binDir, "external")
+	if err := os.Symlink("/usr/local/bin/node", externalLink); err != nil {
+		t.Fatal(err)
+	}
+	relExternal, err := filepath.Rel(buildDir, externalLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isStaleBinSymlink(relExternal, externalLink, buildDir) {
+		t.Error("isStaleBinSymlink() = true for a symlink outside the build dir, want false")
+	}
+
+	outsideBin := filepath.Join(buildDir, "node_modules", "foo", "stale")
+	if err := os.MkdirAll(filepath.Dir(outsideBin), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(absBuildDir, "node_modules", "foo", "bin"), outsideBin); err != nil {
+		t.Fatal(err)
+	}
+	relOutsideBin, err := filepath.Rel(buildDir, outsideBin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if isStaleBinSymlink(relOutsideBin, outsideBin, buildDir) {
+		t.Error("isStaleBinSymlink() = true for a symlink outside node_modules/.bin, want false")
+	}
+}
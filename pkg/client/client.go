@@ -0,0 +1,124 @@
+// Package client is a small SDK for talking to a deployer daemon's HTTP API
+// from other Go programs (CLIs, CI steps, dashboards).
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/yourusername/deployer/pkg/daemon/db"
+)
+
+// Client talks to a single deployer daemon instance.
+type Client struct {
+	BaseURL string
+	APIKey  string
+
+	HTTPClient *http.Client
+}
+
+// New creates a Client for the daemon at baseURL, authenticating with
+// apiKey.
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    baseURL,
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// DeployRequest mirrors the daemon's POST /deploy payload.
+type DeployRequest struct {
+	RepoURL    string `json:"repo_url"`
+	Ref        string `json:"ref,omitempty"`
+	Depth      int    `json:"depth,omitempty"`
+	Submodules bool   `json:"submodules,omitempty"`
+	AuthRef    string `json:"auth_ref,omitempty"`
+}
+
+// DeployResponse is returned by a successful enqueue.
+type DeployResponse struct {
+	JobID string `json:"job_id"`
+}
+
+// Deploy enqueues a deployment and returns the new job's ID.
+func (c *Client) Deploy(req DeployRequest) (string, error) {
+	var resp DeployResponse
+	if err := c.do(http.MethodPost, "/deploy", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.JobID, nil
+}
+
+// Job fetches a single job by ID.
+func (c *Client) Job(id string) (*db.Job, error) {
+	var job db.Job
+	if err := c.do(http.MethodGet, "/jobs/"+id, nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Jobs lists every job known to the daemon.
+func (c *Client) Jobs() ([]*db.Job, error) {
+	var jobs []*db.Job
+	if err := c.do(http.MethodGet, "/jobs", nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// RepoJobs lists the jobs run against repoURL.
+func (c *Client) RepoJobs(repoURL string) ([]*db.Job, error) {
+	var jobs []*db.Job
+	if err := c.do(http.MethodGet, "/repos/"+url.PathEscape(repoURL)+"/jobs", nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CancelJob requests cancellation of a running job.
+func (c *Client) CancelJob(id string) error {
+	return c.do(http.MethodPost, "/jobs/"+id+"/cancel", nil, nil)
+}
+
+func (c *Client) do(method, path string, body, out any) error {
+	var reqBody *bytes.Buffer
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(payload)
+	} else {
+		reqBody = bytes.NewBuffer(nil)
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: unexpected status %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
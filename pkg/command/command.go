@@ -0,0 +1,48 @@
+// Package command runs shell commands with a timeout and a working directory,
+// returning combined stdout/stderr. It is the default runner used by
+// pkg/deploy.Deployer.
+package command
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Runner matches the signature pkg/deploy.Deployer and pkg/framework expect
+// for executing a command: a cancellable context, working directory,
+// timeout, extra environment variables, program name, and arguments. The
+// context lets a caller (e.g. a job cancellation request) kill the command
+// early; the timeout is an upper bound regardless.
+type Runner func(ctx context.Context, dir string, timeout time.Duration, env []string, name string, args ...string) (string, error)
+
+// Run executes name with args in dir, killing it if ctx is cancelled or if
+// it exceeds timeout, whichever comes first.
+func Run(ctx context.Context, dir string, timeout time.Duration, name string, args ...string) (string, error) {
+	return RunWithEnv(ctx, dir, timeout, nil, name, args...)
+}
+
+// RunWithEnv is Run with extra environment variables (NAME=value) appended
+// on top of the current process's environment. It also satisfies Runner.
+func RunWithEnv(ctx context.Context, dir string, timeout time.Duration, env []string, name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return string(output), context.DeadlineExceeded
+		case context.Canceled:
+			return string(output), context.Canceled
+		}
+	}
+	return string(output), err
+}
@@ -0,0 +1,202 @@
+// Package daemon turns pkg/deploy.Deployer into a long-running service:
+// deploy requests are enqueued, persisted, and drained by a worker pool
+// instead of running synchronously inside the HTTP handler.
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourusername/deployer/pkg/daemon/db"
+	"github.com/yourusername/deployer/pkg/deploy"
+	"github.com/yourusername/deployer/pkg/types"
+)
+
+// Workers is the default size of the worker pool draining the job queue.
+const Workers = 2
+
+// Daemon owns the job queue, the per-repo deploy locks, and the workers that
+// drain the queue by invoking the Deployer.
+type Daemon struct {
+	db       *db.DB
+	deployer *deploy.Deployer
+	buildDir string
+
+	queue chan string // job IDs waiting to run
+
+	repoMu   sync.Mutex // guards repoLock
+	repoLock map[string]*sync.Mutex
+
+	cancelMu sync.Mutex // guards cancels
+	cancels  map[string]context.CancelFunc
+
+	// Publish, if set, runs after a deployment succeeds (e.g. to atomically
+	// swap the served static directory). A Publish error fails the job.
+	Publish func(deployment *types.Deployment) error
+}
+
+// New creates a Daemon backed by store and deployer. buildDir is the parent
+// directory under which each job gets its own build folder.
+func New(store *db.DB, deployer *deploy.Deployer, buildDir string) *Daemon {
+	d := &Daemon{
+		db:       store,
+		deployer: deployer,
+		buildDir: buildDir,
+		queue:    make(chan string, 256),
+		repoLock: make(map[string]*sync.Mutex),
+		cancels:  make(map[string]context.CancelFunc),
+	}
+	for i := 0; i < Workers; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+// EnqueueOptions customizes a single deployment request.
+type EnqueueOptions struct {
+	Ref        string
+	Depth      int
+	Submodules bool
+	AuthRef    string
+}
+
+// Enqueue persists a new queued job for repoURL and schedules it for
+// execution, returning its job ID immediately.
+func (d *Daemon) Enqueue(repoURL string, opts EnqueueOptions) (string, error) {
+	job := &db.Job{
+		ID:         uuid.NewString(),
+		RepoURL:    repoURL,
+		Ref:        opts.Ref,
+		Depth:      opts.Depth,
+		Submodules: opts.Submodules,
+		AuthRef:    opts.AuthRef,
+		Status:     db.StatusQueued,
+		CreatedAt:  time.Now(),
+	}
+	if err := d.db.CreateJob(job); err != nil {
+		return "", fmt.Errorf("create job: %w", err)
+	}
+	d.queue <- job.ID
+	return job.ID, nil
+}
+
+// Cancel cancels the context of a running job. It is a no-op if the job has
+// already finished or has not started running yet.
+func (d *Daemon) Cancel(jobID string) bool {
+	d.cancelMu.Lock()
+	cancel, ok := d.cancels[jobID]
+	d.cancelMu.Unlock()
+	if !ok {
+		return false
+	}
+	cancel()
+	return true
+}
+
+// Job, Jobs and RepoJobs expose the persisted history to the HTTP layer.
+func (d *Daemon) Job(id string) (*db.Job, error)             { return d.db.GetJob(id) }
+func (d *Daemon) Jobs() ([]*db.Job, error)                   { return d.db.ListJobs() }
+func (d *Daemon) RepoJobs(repoURL string) ([]*db.Job, error) { return d.db.ListJobsByRepo(repoURL) }
+
+func (d *Daemon) worker() {
+	for jobID := range d.queue {
+		d.run(jobID)
+	}
+}
+
+func (d *Daemon) run(jobID string) {
+	job, err := d.db.GetJob(jobID)
+	if err != nil {
+		return
+	}
+
+	lock := d.lockFor(job.RepoURL)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancelMu.Lock()
+	d.cancels[job.ID] = cancel
+	d.cancelMu.Unlock()
+	defer func() {
+		d.cancelMu.Lock()
+		delete(d.cancels, job.ID)
+		d.cancelMu.Unlock()
+		cancel()
+	}()
+
+	now := time.Now()
+	job.Status = db.StatusRunning
+	job.StartedAt = &now
+	_ = d.db.UpdateJob(job)
+
+	deployment := &types.Deployment{
+		ID:         job.ID,
+		RepoURL:    job.RepoURL,
+		BuildDir:   fmt.Sprintf("%s/%s", d.buildDir, job.ID),
+		Ref:        job.Ref,
+		Depth:      job.Depth,
+		Submodules: job.Submodules,
+		AuthRef:    job.AuthRef,
+	}
+	resp := d.deployer.Execute(ctx, deployment)
+
+	if deployment.Status == "success" && !deployment.Cached && d.Publish != nil {
+		if err := d.Publish(deployment); err != nil {
+			deployment.Status = "failed"
+			deployment.Error = fmt.Sprintf("Publish failed: %v", err)
+		}
+	}
+
+	// The build dir has already been published (or cached) by this point, so
+	// it's safe to reclaim the disk regardless of how the job ended.
+	if err := os.RemoveAll(deployment.BuildDir); err != nil {
+		log.Printf("failed to clean up build dir %s: %v", deployment.BuildDir, err)
+	}
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Outputs = deployment.Outputs
+	job.DeployPath = deployment.DeployPath
+	job.Error = deployment.Error
+	job.CommitSHA = deployment.CommitSHA
+
+	switch {
+	case ctx.Err() == context.Canceled:
+		job.Status = db.StatusCancelled
+	case deployment.Status == "success":
+		job.Status = db.StatusSucceeded
+	default:
+		job.Status = db.StatusFailed
+	}
+	_ = d.db.UpdateJob(job)
+
+	if job.Status == db.StatusSucceeded {
+		_ = d.db.UpsertRepo(&db.Repo{
+			URL:            job.RepoURL,
+			LastCommitSHA:  job.CommitSHA,
+			LastDeployedAt: &finished,
+			LastJobID:      job.ID,
+		})
+	}
+
+	_ = resp // response body is reconstructed from the job row by the HTTP layer
+}
+
+func (d *Daemon) lockFor(repoURL string) *sync.Mutex {
+	d.repoMu.Lock()
+	defer d.repoMu.Unlock()
+
+	lock, ok := d.repoLock[repoURL]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.repoLock[repoURL] = lock
+	}
+	return lock
+}
@@ -0,0 +1,260 @@
+// Package db provides the SQLite-backed persistence for the deployment
+// daemon: the job queue/history and per-repo deployment state.
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/yourusername/deployer/pkg/types"
+)
+
+// Job statuses, in the order a job normally moves through them.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// Job is a single persisted deployment request.
+type Job struct {
+	ID         string        `json:"id"`
+	RepoURL    string        `json:"repo_url"`
+	Ref        string        `json:"ref,omitempty"`
+	Depth      int           `json:"depth,omitempty"`
+	Submodules bool          `json:"submodules,omitempty"`
+	AuthRef    string        `json:"auth_ref,omitempty"`
+	Status     string        `json:"status"`
+	Error      string        `json:"error,omitempty"`
+	Outputs    types.Outputs `json:"outputs"`
+	CommitSHA  string        `json:"commit_sha,omitempty"`
+	DeployPath string        `json:"deploy_path,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// Repo tracks the last known deployment state of a repository.
+type Repo struct {
+	URL            string     `json:"url"`
+	LastCommitSHA  string     `json:"last_commit_sha,omitempty"`
+	LastDeployedAt *time.Time `json:"last_deployed_at,omitempty"`
+	LastJobID      string     `json:"last_job_id,omitempty"`
+}
+
+// DB wraps a SQLite connection with the queries the daemon needs.
+type DB struct {
+	conn *sql.DB
+}
+
+// Open opens (creating if necessary) the SQLite database at path and applies
+// the schema.
+func Open(path string) (*DB, error) {
+	conn, err := sql.Open("sqlite3", path+"?_journal=WAL&_fk=1")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// The daemon's per-repo locking only serializes jobs of the same repo, so
+	// workers can write concurrently; sqlite3's driver needs a single writer
+	// connection.
+	conn.SetMaxOpenConns(1)
+
+	db := &DB{conn: conn}
+	if err := db.migrate(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (db *DB) migrate() error {
+	_, err := db.conn.Exec(`
+CREATE TABLE IF NOT EXISTS jobs (
+	id           TEXT PRIMARY KEY,
+	repo_url     TEXT NOT NULL,
+	ref          TEXT,
+	depth        INTEGER NOT NULL DEFAULT 0,
+	submodules   BOOLEAN NOT NULL DEFAULT 0,
+	auth_ref     TEXT,
+	status       TEXT NOT NULL,
+	error        TEXT,
+	outputs_json TEXT,
+	commit_sha   TEXT,
+	deploy_path  TEXT,
+	created_at   DATETIME NOT NULL,
+	started_at   DATETIME,
+	finished_at  DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_repo_url ON jobs(repo_url);
+
+CREATE TABLE IF NOT EXISTS repos (
+	url              TEXT PRIMARY KEY,
+	last_commit_sha  TEXT,
+	last_deployed_at DATETIME,
+	last_job_id      TEXT
+);
+`)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) Close() error {
+	return db.conn.Close()
+}
+
+// CreateJob inserts a newly queued job.
+func (db *DB) CreateJob(job *Job) error {
+	outputs, err := json.Marshal(job.Outputs)
+	if err != nil {
+		return fmt.Errorf("marshal outputs: %w", err)
+	}
+	_, err = db.conn.Exec(
+		`INSERT INTO jobs (id, repo_url, ref, depth, submodules, auth_ref, status, outputs_json, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.RepoURL, job.Ref, job.Depth, job.Submodules, job.AuthRef, job.Status, outputs, job.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert job: %w", err)
+	}
+	return nil
+}
+
+// UpdateJob persists the mutable fields of job (status, error, outputs,
+// timestamps, resolved commit).
+func (db *DB) UpdateJob(job *Job) error {
+	outputs, err := json.Marshal(job.Outputs)
+	if err != nil {
+		return fmt.Errorf("marshal outputs: %w", err)
+	}
+	_, err = db.conn.Exec(
+		`UPDATE jobs SET status = ?, error = ?, outputs_json = ?, commit_sha = ?, deploy_path = ?, started_at = ?, finished_at = ? WHERE id = ?`,
+		job.Status, job.Error, outputs, job.CommitSHA, job.DeployPath, job.StartedAt, job.FinishedAt, job.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("update job: %w", err)
+	}
+	return nil
+}
+
+// GetJob fetches a single job by ID.
+func (db *DB) GetJob(id string) (*Job, error) {
+	row := db.conn.QueryRow(
+		`SELECT id, repo_url, ref, depth, submodules, auth_ref, status, error, outputs_json, commit_sha, deploy_path, created_at, started_at, finished_at FROM jobs WHERE id = ?`,
+		id,
+	)
+	return scanJob(row)
+}
+
+// ListJobs returns all jobs, most recently created first.
+func (db *DB) ListJobs() ([]*Job, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, repo_url, ref, depth, submodules, auth_ref, status, error, outputs_json, commit_sha, deploy_path, created_at, started_at, finished_at FROM jobs ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list jobs: %w", err)
+	}
+	defer rows.Close()
+	return scanJobs(rows)
+}
+
+// ListJobsByRepo returns all jobs for repoURL, most recently created first.
+func (db *DB) ListJobsByRepo(repoURL string) ([]*Job, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, repo_url, ref, depth, submodules, auth_ref, status, error, outputs_json, commit_sha, deploy_path, created_at, started_at, finished_at FROM jobs WHERE repo_url = ? ORDER BY created_at DESC`,
+		repoURL,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list jobs by repo: %w", err)
+	}
+	defer rows.Close()
+	return scanJobs(rows)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	var outputs sql.NullString
+	var errStr, ref, authRef, sha, deployPath sql.NullString
+	var startedAt, finishedAt sql.NullTime
+
+	err := row.Scan(&job.ID, &job.RepoURL, &ref, &job.Depth, &job.Submodules, &authRef, &job.Status, &errStr, &outputs, &sha, &deployPath, &job.CreatedAt, &startedAt, &finishedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan job: %w", err)
+	}
+
+	job.Ref = ref.String
+	job.AuthRef = authRef.String
+	job.Error = errStr.String
+	job.CommitSHA = sha.String
+	job.DeployPath = deployPath.String
+	if outputs.Valid && outputs.String != "" {
+		if err := json.Unmarshal([]byte(outputs.String), &job.Outputs); err != nil {
+			return nil, fmt.Errorf("unmarshal outputs: %w", err)
+		}
+	}
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
+
+func scanJobs(rows *sql.Rows) ([]*Job, error) {
+	var jobs []*Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// UpsertRepo records the outcome of a successful deployment against repoURL.
+func (db *DB) UpsertRepo(repo *Repo) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO repos (url, last_commit_sha, last_deployed_at, last_job_id) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(url) DO UPDATE SET last_commit_sha = excluded.last_commit_sha, last_deployed_at = excluded.last_deployed_at, last_job_id = excluded.last_job_id`,
+		repo.URL, repo.LastCommitSHA, repo.LastDeployedAt, repo.LastJobID,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert repo: %w", err)
+	}
+	return nil
+}
+
+// GetRepo fetches the tracked state for repoURL, or (nil, sql.ErrNoRows) if
+// it has never been deployed.
+func (db *DB) GetRepo(repoURL string) (*Repo, error) {
+	var repo Repo
+	var sha sql.NullString
+	var deployedAt sql.NullTime
+	var lastJobID sql.NullString
+
+	row := db.conn.QueryRow(`SELECT url, last_commit_sha, last_deployed_at, last_job_id FROM repos WHERE url = ?`, repoURL)
+	if err := row.Scan(&repo.URL, &sha, &deployedAt, &lastJobID); err != nil {
+		return nil, err
+	}
+	repo.LastCommitSHA = sha.String
+	repo.LastJobID = lastJobID.String
+	if deployedAt.Valid {
+		repo.LastDeployedAt = &deployedAt.Time
+	}
+	return &repo, nil
+}
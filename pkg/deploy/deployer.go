@@ -2,25 +2,51 @@
 package deploy
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/yourusername/deployer/pkg/buildcache"
 	"github.com/yourusername/deployer/pkg/command"
+	"github.com/yourusername/deployer/pkg/framework"
+	"github.com/yourusername/deployer/pkg/prisma/binaries"
 	"github.com/yourusername/deployer/pkg/types"
+	"github.com/yourusername/deployer/pkg/vcs"
 )
 
+// cloneTimeout bounds the clone stage so a stalled transport (flaky network,
+// a server that never closes the connection) can't pin a worker forever;
+// only an explicit job cancellation could otherwise unstick it.
+const cloneTimeout = 5 * time.Minute
+
 type Deployer struct {
-	runner commandRunner
+	runner command.Runner
+
+	// CommitCache, if set, is consulted right after a clone resolves a
+	// commit SHA. A hit skips install/build entirely and reuses the
+	// previous deploy path.
+	CommitCache func(repoURL, commitSHA string) (deployPath string, ok bool)
+
+	// BuildCacheDir, if non-empty, enables restoring/archiving
+	// node_modules (and framework cache dirs) across builds that share a
+	// lockfile. The cache key is the lockfile hash alone; the commit SHA is
+	// not part of it, so different commits with an unchanged lockfile share
+	// an entry. BuildCacheMaxBytes bounds its total size; entries are
+	// LRU-evicted above it.
+	BuildCacheDir      string
+	BuildCacheMaxBytes int64
 }
 
-type commandRunner func(dir string, timeout time.Duration, name string, args ...string) (string, error)
-
-func NewDeployer(runner commandRunner) *Deployer {
+func NewDeployer(runner command.Runner) *Deployer {
 	return &Deployer{runner: runner}
 }
 
-func (d *Deployer) Execute(deployment *types.Deployment) *types.DeploymentResponse {
+// Execute runs a deployment end to end. ctx governs the whole run: cancelling
+// it (e.g. from a job cancellation request) aborts whichever stage is
+// currently in flight.
+func (d *Deployer) Execute(ctx context.Context, deployment *types.Deployment) *types.DeploymentResponse {
 	defer func() {
 		if r := recover(); r != nil {
 			deployment.Error = fmt.Sprintf("Panic: %v", r)
@@ -34,64 +60,167 @@ func (d *Deployer) Execute(deployment *types.Deployment) *types.DeploymentRespon
 	}
 
 	// Clone repository
-	output, err := d.runner("", 5*time.Minute, "git", "clone", deployment.RepoURL, deployment.BuildDir)
-	deployment.Outputs.Clone = output
+	auth, err := vcs.ResolveAuth(deployment.AuthRef)
 	if err != nil {
+		return d.fail(deployment, "Auth setup failed", err)
+	}
+	cloneCtx, cancelClone := context.WithTimeout(ctx, cloneTimeout)
+	defer cancelClone()
+	commitSHA, err := vcs.Clone(cloneCtx, vcs.VCSOptions{
+		URL:        deployment.RepoURL,
+		Dest:       deployment.BuildDir,
+		Ref:        deployment.Ref,
+		Depth:      deployment.Depth,
+		Submodules: deployment.Submodules,
+		Auth:       auth,
+	})
+	if err != nil {
+		deployment.Outputs.Clone = err.Error()
 		return d.fail(deployment, "Clone failed", err)
 	}
-
-	// Framework-specific setup
-	switch deployment.Framework {
-	case "node-prisma":
-		if err := d.handlePrisma(deployment); err != nil {
-			return d.fail(deployment, "Prisma setup failed", err)
+	deployment.CommitSHA = commitSHA
+	deployment.Outputs.Clone = fmt.Sprintf("Cloned %s at %s", deployment.RepoURL, commitSHA)
+
+	if d.CommitCache != nil {
+		if deployPath, ok := d.CommitCache(deployment.RepoURL, commitSHA); ok {
+			deployment.Status = "success"
+			deployment.DeployPath = deployPath
+			deployment.Cached = true
+			return &types.DeploymentResponse{Deployment: deployment}
 		}
 	}
 
-	// Install dependencies
-	output, err = d.runner(deployment.BuildDir, 10*time.Minute, "npm", "install")
-	deployment.Outputs.Install = output
+	// prisma/schema.prisma, if present, needs `prisma generate`/`migrate
+	// deploy` before any framework's install step runs.
+	if err := d.handlePrisma(ctx, deployment); err != nil {
+		return d.fail(deployment, "Prisma setup failed", err)
+	}
+
+	fw, err := d.resolveFramework(deployment)
 	if err != nil {
-		return d.fail(deployment, "npm install failed", err)
+		return d.fail(deployment, "Framework resolution failed", err)
+	}
+	deployment.Framework = fw.Name()
+
+	cacheKey, cacheHit := d.restoreBuildCache(deployment)
+
+	if !cacheHit {
+		if err := fw.Install(ctx, deployment.BuildDir); err != nil {
+			deployment.Outputs.Install, _ = fw.Output()
+			return d.fail(deployment, fw.Name()+" install failed", err)
+		}
 	}
 
-	// Build project
-	output, err = d.runner(deployment.BuildDir, 10*time.Minute, "npm", "run", "build")
-	deployment.Outputs.Build = output
+	outputDir, err := fw.Build(ctx, deployment.BuildDir)
+	install, build := fw.Output()
+	if cacheHit {
+		deployment.Outputs.Install = "restored from build cache"
+	} else {
+		deployment.Outputs.Install = install
+	}
+	deployment.Outputs.Build = build
 	if err != nil {
-		return d.fail(deployment, "npm build failed", err)
+		return d.fail(deployment, fw.Name()+" build failed", err)
 	}
 
-	// Verify build output
-	if _, err := os.Stat(filepath.Join(deployment.BuildDir, "dist")); err != nil {
-		return d.fail(deployment, "Build output missing", err)
+	if !cacheHit && cacheKey != "" {
+		d.storeBuildCache(cacheKey, deployment.BuildDir, fw.Name())
 	}
 
 	deployment.Status = "success"
-	deployment.DeployPath = "/deployments/" + deployment.ID + "/dist/"
+	deployment.OutputDir = filepath.Clean(outputDir)
+	deployment.DeployPath = "/deployments/" + deployment.ID + "/" + deployment.OutputDir + "/"
 	return &types.DeploymentResponse{Deployment: deployment}
 }
 
-func (d *Deployer) handlePrisma(deployment *types.Deployment) error {
+// restoreBuildCache tries to restore a cached node_modules (and framework
+// cache dirs) keyed by deployment's lockfile (the commit SHA is not part of
+// the key). It returns the cache key (so a miss can be stored under it
+// later) and whether it was a hit.
+func (d *Deployer) restoreBuildCache(deployment *types.Deployment) (key string, hit bool) {
+	if d.BuildCacheDir == "" {
+		return "", false
+	}
+
+	key, err := buildcache.Key(deployment.BuildDir)
+	if err != nil {
+		deployment.Outputs.Cache = "skipped: " + err.Error()
+		return "", false
+	}
+
+	hit, err = buildcache.Restore(d.BuildCacheDir, key, deployment.BuildDir)
+	if err != nil {
+		deployment.Outputs.Cache = fmt.Sprintf("miss (restore failed: %v)", err)
+		return key, false
+	}
+	if hit {
+		deployment.Outputs.Cache = "hit"
+	} else {
+		deployment.Outputs.Cache = "miss"
+	}
+	return key, hit
+}
+
+func (d *Deployer) storeBuildCache(key, buildDir, frameworkName string) {
+	if err := buildcache.Store(d.BuildCacheDir, key, buildDir, buildcache.DirsFor(frameworkName)); err != nil {
+		return
+	}
+	buildcache.Evict(d.BuildCacheDir, d.BuildCacheMaxBytes)
+}
+
+// resolveFramework honors an explicit Framework override on deployment,
+// falling back to auto-detection.
+func (d *Deployer) resolveFramework(deployment *types.Deployment) (framework.Framework, error) {
+	if deployment.Framework != "" {
+		fw, ok := framework.ByName(deployment.Framework, d.runner)
+		if !ok {
+			return nil, fmt.Errorf("unknown framework %q", deployment.Framework)
+		}
+		return fw, nil
+	}
+	return framework.Detect(deployment.BuildDir, d.runner), nil
+}
+
+func (d *Deployer) handlePrisma(ctx context.Context, deployment *types.Deployment) error {
 	// Check for prisma schema
 	if _, err := os.Stat(filepath.Join(deployment.BuildDir, "prisma/schema.prisma")); err != nil {
 		return nil // No prisma needed
 	}
 
-	// Run prisma commands
-	output, err := d.runner(deployment.BuildDir, 2*time.Minute, "npx", "prisma", "generate")
+	// Point prisma at the daemon's pre-warmed engine cache instead of
+	// letting it download its own copy into node_modules/.prisma.
+	env, err := prismaEngineEnv()
+	if err != nil {
+		return fmt.Errorf("resolve cached prisma engines: %w", err)
+	}
+
+	output, err := command.RunWithEnv(ctx, deployment.BuildDir, 2*time.Minute, env, "npx", "prisma", "generate")
 	deployment.Outputs.Prisma = output
 	if err != nil {
 		return err
 	}
 
-	output, err = d.runner(deployment.BuildDir, 2*time.Minute, "npx", "prisma", "migrate", "deploy")
+	output, err = command.RunWithEnv(ctx, deployment.BuildDir, 2*time.Minute, env, "npx", "prisma", "migrate", "deploy")
 	deployment.Outputs.Prisma += "\n" + output
 	return err
 }
 
+// prismaEngineEnv resolves the PRISMA_*_BINARY environment variables
+// pointing at the engines binaries.PrefetchAll cached at daemon startup.
+func prismaEngineEnv() ([]string, error) {
+	dir, err := binaries.GlobalCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	platform, err := binaries.Platform()
+	if err != nil {
+		return nil, err
+	}
+	return binaries.Env(dir, platform), nil
+}
+
 func (d *Deployer) fail(deployment *types.Deployment, message string, err error) *types.DeploymentResponse {
 	deployment.Status = "failed"
 	deployment.Error = fmt.Sprintf("%s: %v", message, err)
 	return &types.DeploymentResponse{Deployment: deployment}
-}
\ No newline at end of file
+}
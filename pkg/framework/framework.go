@@ -0,0 +1,144 @@
+// Package framework detects which web framework a cloned repository uses
+// and knows how to install its dependencies and build it, so the deployer
+// no longer has to hardcode npm install/build and a "dist" output path.
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yourusername/deployer/pkg/command"
+)
+
+const (
+	installTimeout = 10 * time.Minute
+	buildTimeout   = 10 * time.Minute
+)
+
+// Framework installs dependencies and builds a project of a specific kind.
+type Framework interface {
+	// Name identifies the framework, e.g. for logging or an explicit
+	// override.
+	Name() string
+
+	// Detect reports whether dir looks like a project this Framework
+	// builds.
+	Detect(dir string) bool
+
+	// Install fetches dependencies in dir.
+	Install(ctx context.Context, dir string) error
+
+	// Build compiles dir and returns the directory (relative to dir)
+	// containing the deployable output.
+	Build(ctx context.Context, dir string) (outputDir string, err error)
+
+	// Env returns extra environment variables to set for Install/Build,
+	// e.g. to force a non-interactive or production build.
+	Env() []string
+
+	// Output returns the combined stdout/stderr of the most recent Install
+	// and Build calls, for the deployer to record onto Outputs.
+	Output() (install, build string)
+}
+
+// registry is tried in order; the first Framework whose Detect matches wins.
+// Static is intentionally last: every directory "matches" it.
+func registry(runner command.Runner) []Framework {
+	return []Framework{
+		newNextFramework(runner),
+		newNuxtFramework(runner),
+		newSvelteKitFramework(runner),
+		newAstroFramework(runner),
+		newHugoFramework(runner),
+		newViteFramework(runner),
+		newCRAFramework(runner),
+		newStaticFramework(runner),
+	}
+}
+
+// Detect inspects dir and returns the Framework that should build it. It
+// always succeeds: an unrecognized directory falls back to StaticFramework.
+func Detect(dir string, runner command.Runner) Framework {
+	for _, fw := range registry(runner) {
+		if fw.Detect(dir) {
+			return fw
+		}
+	}
+	// Unreachable: staticFramework.Detect always returns true.
+	return newStaticFramework(runner)
+}
+
+// ByName returns the named Framework without running detection, for callers
+// that want to force a specific builder (e.g. a DeployRequest override).
+func ByName(name string, runner command.Runner) (Framework, bool) {
+	for _, fw := range registry(runner) {
+		if fw.Name() == name {
+			return fw, true
+		}
+	}
+	return nil, false
+}
+
+// packageJSON is the subset of package.json fields frameworks need to
+// inspect to distinguish themselves from one another.
+type packageJSON struct {
+	Scripts         map[string]string `json:"scripts"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+func readPackageJSON(dir string) (*packageJSON, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, false
+	}
+	var pkg packageJSON
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, false
+	}
+	return &pkg, true
+}
+
+func (p *packageJSON) hasDependency(name string) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.Dependencies[name]
+	if ok {
+		return true
+	}
+	_, ok = p.DevDependencies[name]
+	return ok
+}
+
+func fileExists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func globExists(dir, pattern string) bool {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	return err == nil && len(matches) > 0
+}
+
+func npmInstall(ctx context.Context, runner command.Runner, dir string, env []string) (string, error) {
+	if fileExists(dir, "package-lock.json") {
+		return runner(ctx, dir, installTimeout, env, "npm", "ci")
+	}
+	return runner(ctx, dir, installTimeout, env, "npm", "install")
+}
+
+func npmRunBuild(ctx context.Context, runner command.Runner, dir string, script string, env []string) (string, error) {
+	return runner(ctx, dir, buildTimeout, env, "npm", "run", script)
+}
+
+func buildOutputMissing(dir, outputDir string) error {
+	if !fileExists(dir, outputDir) {
+		return fmt.Errorf("build output %q not found in %s", outputDir, dir)
+	}
+	return nil
+}
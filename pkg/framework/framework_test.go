@@ -0,0 +1,84 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHugoDetect(t *testing.T) {
+	cases := []struct {
+		name  string
+		files []string
+		dirs  []string
+		want  bool
+	}{
+		{name: "hugo.toml alone", files: []string{"hugo.toml"}, want: true},
+		{name: "hugo.yaml alone", files: []string{"hugo.yaml"}, want: true},
+		{name: "bare config.toml with no content layout", files: []string{"config.toml"}, want: false},
+		{name: "config.toml with content dir", files: []string{"config.toml"}, dirs: []string{"content"}, want: true},
+		{name: "config.toml with layouts dir", files: []string{"config.toml"}, dirs: []string{"layouts"}, want: true},
+		{name: "no hugo markers", files: []string{"package.json"}, want: false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tc.files {
+				writeFile(t, dir, f, "")
+			}
+			for _, d := range tc.dirs {
+				if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fw := newHugoFramework(nil)
+			if got := fw.Detect(dir); got != tc.want {
+				t.Errorf("Detect() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectFallsBackToStatic(t *testing.T) {
+	dir := t.TempDir()
+	fw := Detect(dir, nil)
+	if fw.Name() != "static" {
+		t.Errorf("Detect() on an empty dir = %q, want %q", fw.Name(), "static")
+	}
+}
+
+func TestDetectHugoBeforeStaticWithContentLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.toml", "")
+	if err := os.MkdirAll(filepath.Join(dir, "content"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fw := Detect(dir, nil)
+	if fw.Name() != "hugo" {
+		t.Errorf("Detect() = %q, want %q", fw.Name(), "hugo")
+	}
+}
+
+func TestDetectDoesNotMatchHugoOnBareConfigToml(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "config.toml", "")
+
+	fw := Detect(dir, nil)
+	if fw.Name() == "hugo" {
+		t.Errorf("Detect() matched hugo on a bare config.toml with no content layout")
+	}
+}
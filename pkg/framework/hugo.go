@@ -0,0 +1,50 @@
+package framework
+
+import (
+	"context"
+
+	"github.com/yourusername/deployer/pkg/command"
+)
+
+// hugoFramework builds Hugo sites. Hugo has no install step and its output
+// lands in public/ by convention.
+type hugoFramework struct {
+	runner   command.Runner
+	buildLog string
+}
+
+func newHugoFramework(runner command.Runner) Framework {
+	return &hugoFramework{runner: runner}
+}
+
+func (f *hugoFramework) Name() string { return "hugo" }
+
+func (f *hugoFramework) Detect(dir string) bool {
+	if globExists(dir, "hugo.toml") || globExists(dir, "hugo.yaml") {
+		return true
+	}
+	// config.toml alone is too generic (plenty of non-Hugo JS projects ship
+	// one at the repo root); also require a Hugo content layout before
+	// matching on it.
+	if !globExists(dir, "config.toml") {
+		return false
+	}
+	return fileExists(dir, "content") || fileExists(dir, "layouts")
+}
+
+func (f *hugoFramework) Install(ctx context.Context, dir string) error {
+	return nil // Hugo ships as a single binary; nothing to install.
+}
+
+func (f *hugoFramework) Build(ctx context.Context, dir string) (string, error) {
+	output, err := f.runner(ctx, dir, buildTimeout, nil, "hugo", "--minify")
+	f.buildLog = output
+	if err != nil {
+		return "", err
+	}
+	return "public", buildOutputMissing(dir, "public")
+}
+
+func (f *hugoFramework) Env() []string { return nil }
+
+func (f *hugoFramework) Output() (install, build string) { return "", f.buildLog }
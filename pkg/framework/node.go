@@ -0,0 +1,202 @@
+package framework
+
+import (
+	"context"
+
+	"github.com/yourusername/deployer/pkg/command"
+)
+
+// nonInteractiveEnv is set on every npm-based Framework so installs/builds
+// never block on an interactive prompt (e.g. npm's update-notifier or a
+// package's postinstall wizard) when run unattended by the daemon.
+var nonInteractiveEnv = []string{"CI=true"}
+
+// nodeFramework holds the bits every npm-based Framework needs: the runner,
+// its captured logs, and a build script name.
+type nodeFramework struct {
+	runner      command.Runner
+	buildScript string
+	env         []string
+
+	installLog string
+	buildLog   string
+}
+
+func (n *nodeFramework) Install(ctx context.Context, dir string) error {
+	output, err := npmInstall(ctx, n.runner, dir, n.env)
+	n.installLog = output
+	return err
+}
+
+func (n *nodeFramework) Build(ctx context.Context, dir string) (string, error) {
+	output, err := npmRunBuild(ctx, n.runner, dir, n.buildScript, n.env)
+	n.buildLog = output
+	return "", err // outputDir is filled in by the embedding type
+}
+
+func (n *nodeFramework) Env() []string { return n.env }
+
+func (n *nodeFramework) Output() (install, build string) { return n.installLog, n.buildLog }
+
+// ---- Vite / Create React App ----
+
+// viteFramework builds Vite (and other `vite build`-based) projects, whose
+// output lands in dist/ by convention.
+type viteFramework struct{ nodeFramework }
+
+func newViteFramework(runner command.Runner) Framework {
+	return &viteFramework{nodeFramework{runner: runner, buildScript: "build", env: nonInteractiveEnv}}
+}
+
+func (f *viteFramework) Name() string { return "vite" }
+
+func (f *viteFramework) Detect(dir string) bool {
+	pkg, ok := readPackageJSON(dir)
+	if !ok {
+		return false
+	}
+	return pkg.hasDependency("vite") && fileExists(dir, "package.json")
+}
+
+func (f *viteFramework) Build(ctx context.Context, dir string) (string, error) {
+	if _, err := f.nodeFramework.Build(ctx, dir); err != nil {
+		return "", err
+	}
+	return "dist", buildOutputMissing(dir, "dist")
+}
+
+// craFramework builds Create React App projects, whose output lands in
+// build/ by convention.
+type craFramework struct{ nodeFramework }
+
+func newCRAFramework(runner command.Runner) Framework {
+	return &craFramework{nodeFramework{runner: runner, buildScript: "build", env: nonInteractiveEnv}}
+}
+
+func (f *craFramework) Name() string { return "cra" }
+
+func (f *craFramework) Detect(dir string) bool {
+	pkg, ok := readPackageJSON(dir)
+	if !ok {
+		return false
+	}
+	return pkg.hasDependency("react-scripts")
+}
+
+func (f *craFramework) Build(ctx context.Context, dir string) (string, error) {
+	if _, err := f.nodeFramework.Build(ctx, dir); err != nil {
+		return "", err
+	}
+	return "build", buildOutputMissing(dir, "build")
+}
+
+// ---- Next.js ----
+
+// nextFramework builds Next.js projects. If the project is configured with
+// `output: "standalone"` the deployable output is .next/standalone, which
+// bundles its own server; otherwise it's the .next/ directory served behind
+// `next start`.
+type nextFramework struct{ nodeFramework }
+
+func newNextFramework(runner command.Runner) Framework {
+	return &nextFramework{nodeFramework{runner: runner, buildScript: "build", env: nonInteractiveEnv}}
+}
+
+func (f *nextFramework) Name() string { return "next" }
+
+func (f *nextFramework) Detect(dir string) bool {
+	pkg, ok := readPackageJSON(dir)
+	if !ok {
+		return false
+	}
+	return pkg.hasDependency("next") || globExists(dir, "next.config.*")
+}
+
+func (f *nextFramework) Build(ctx context.Context, dir string) (string, error) {
+	if _, err := f.nodeFramework.Build(ctx, dir); err != nil {
+		return "", err
+	}
+	if fileExists(dir, ".next/standalone") {
+		return ".next/standalone", nil
+	}
+	return ".next", buildOutputMissing(dir, ".next")
+}
+
+// ---- Nuxt ----
+
+// nuxtFramework builds Nuxt 3 projects, whose output lands in .output/.
+type nuxtFramework struct{ nodeFramework }
+
+func newNuxtFramework(runner command.Runner) Framework {
+	return &nuxtFramework{nodeFramework{runner: runner, buildScript: "build", env: nonInteractiveEnv}}
+}
+
+func (f *nuxtFramework) Name() string { return "nuxt" }
+
+func (f *nuxtFramework) Detect(dir string) bool {
+	pkg, ok := readPackageJSON(dir)
+	if !ok {
+		return false
+	}
+	return pkg.hasDependency("nuxt") || globExists(dir, "nuxt.config.*")
+}
+
+func (f *nuxtFramework) Build(ctx context.Context, dir string) (string, error) {
+	if _, err := f.nodeFramework.Build(ctx, dir); err != nil {
+		return "", err
+	}
+	return ".output", buildOutputMissing(dir, ".output")
+}
+
+// ---- SvelteKit ----
+
+// svelteKitFramework builds SvelteKit projects using the static adapter,
+// whose output lands in build/ by convention.
+type svelteKitFramework struct{ nodeFramework }
+
+func newSvelteKitFramework(runner command.Runner) Framework {
+	return &svelteKitFramework{nodeFramework{runner: runner, buildScript: "build", env: nonInteractiveEnv}}
+}
+
+func (f *svelteKitFramework) Name() string { return "sveltekit" }
+
+func (f *svelteKitFramework) Detect(dir string) bool {
+	pkg, ok := readPackageJSON(dir)
+	if !ok {
+		return false
+	}
+	return pkg.hasDependency("@sveltejs/kit")
+}
+
+func (f *svelteKitFramework) Build(ctx context.Context, dir string) (string, error) {
+	if _, err := f.nodeFramework.Build(ctx, dir); err != nil {
+		return "", err
+	}
+	return "build", buildOutputMissing(dir, "build")
+}
+
+// ---- Astro ----
+
+// astroFramework builds Astro projects, whose output lands in dist/.
+type astroFramework struct{ nodeFramework }
+
+func newAstroFramework(runner command.Runner) Framework {
+	return &astroFramework{nodeFramework{runner: runner, buildScript: "build", env: nonInteractiveEnv}}
+}
+
+func (f *astroFramework) Name() string { return "astro" }
+
+func (f *astroFramework) Detect(dir string) bool {
+	pkg, ok := readPackageJSON(dir)
+	if !ok {
+		return false
+	}
+	return pkg.hasDependency("astro") || globExists(dir, "astro.config.*")
+}
+
+func (f *astroFramework) Build(ctx context.Context, dir string) (string, error) {
+	if _, err := f.nodeFramework.Build(ctx, dir); err != nil {
+		return "", err
+	}
+	return "dist", buildOutputMissing(dir, "dist")
+}
@@ -0,0 +1,30 @@
+package framework
+
+import (
+	"context"
+
+	"github.com/yourusername/deployer/pkg/command"
+)
+
+// staticFramework is the fallback for a plain HTML/CSS/JS site with no
+// build step: the repository itself is the deployable output. Detect always
+// returns true, so it must stay last in the registry.
+type staticFramework struct {
+	runner command.Runner
+}
+
+func newStaticFramework(runner command.Runner) Framework {
+	return &staticFramework{runner: runner}
+}
+
+func (f *staticFramework) Name() string { return "static" }
+
+func (f *staticFramework) Detect(dir string) bool { return true }
+
+func (f *staticFramework) Install(ctx context.Context, dir string) error { return nil }
+
+func (f *staticFramework) Build(ctx context.Context, dir string) (string, error) { return ".", nil }
+
+func (f *staticFramework) Env() []string { return nil }
+
+func (f *staticFramework) Output() (install, build string) { return "", "" }
@@ -0,0 +1,50 @@
+// Package types holds the data structures shared between the deployer,
+// the daemon, and the client SDK.
+package types
+
+// Outputs captures the stdout/stderr produced by each stage of a deployment
+// so it can be replayed later for debugging.
+type Outputs struct {
+	Clone   string `json:"clone,omitempty"`
+	Cache   string `json:"cache,omitempty"`
+	Install string `json:"install,omitempty"`
+	Build   string `json:"build,omitempty"`
+	Prisma  string `json:"prisma,omitempty"`
+}
+
+// Deployment represents a single deployment run of a repository.
+type Deployment struct {
+	ID         string `json:"id"`
+	RepoURL    string `json:"repo_url"`
+	BuildDir   string `json:"build_dir"`
+	Framework  string `json:"framework,omitempty"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DeployPath string `json:"deploy_path,omitempty"`
+	// OutputDir is the framework's build output directory, relative to
+	// BuildDir (e.g. "dist", ".next/standalone").
+	OutputDir string  `json:"output_dir,omitempty"`
+	Outputs   Outputs `json:"outputs"`
+
+	// Ref is the branch, tag, or commit SHA to clone; empty means the
+	// remote's default branch.
+	Ref        string `json:"ref,omitempty"`
+	Depth      int    `json:"depth,omitempty"`
+	Submodules bool   `json:"submodules,omitempty"`
+	AuthRef    string `json:"auth_ref,omitempty"`
+
+	// CommitSHA is filled in by the deployer once the clone resolves to a
+	// concrete commit, so repeat builds of the same commit can be
+	// short-circuited from a cache.
+	CommitSHA string `json:"commit_sha,omitempty"`
+
+	// Cached is true when Deployer.CommitCache short-circuited this run:
+	// install/build were skipped and DeployPath points at a prior run's
+	// output, which is already published.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// DeploymentResponse wraps a Deployment for JSON responses.
+type DeploymentResponse struct {
+	Deployment *Deployment `json:"deployment"`
+}